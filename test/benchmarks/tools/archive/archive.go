@@ -0,0 +1,81 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive persists a stream of bigquery.Benchmark records to disk
+// in the canonical Go benchmark text format (see the parsers package), so
+// large historical runs can be written and replayed without holding every
+// record in memory, and so the same file can be fed straight into
+// benchstat or other tools that already understand Go benchmark output.
+//
+// An archive file begins with a header section recording the git SHA,
+// kernel, and runtime of the run, plus any bigquery.Metadata fields,
+// written once as file-scope "key: value" lines terminated by a blank
+// line. The remainder of the file is one canonical benchmark line per
+// bigquery.Benchmark record.
+//
+// A Metric aggregated from repeated samples (Samples != nil) is written
+// as its mean like any other metric: there is no shared encoding yet for
+// a MetricSamples distribution that both archive and parsers.NewDecoder
+// agree on, so writing one out and reading it back only preserves the
+// mean, not the full distribution.
+package archive
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+// Reserved header keys. These are written and read back as ordinary
+// file-scope configuration lines, but archive.Reader treats them
+// specially rather than folding them into a record's Metadata.
+const (
+	gitSHAKey  = "git-sha"
+	kernelKey  = "kernel"
+	runtimeKey = "runtime"
+	clKey      = "cl"
+)
+
+// Header is the metadata written once at the top of an archive file and
+// applied to every record that follows.
+type Header struct {
+	// GitSHA is the commit under test, if known.
+	GitSHA string
+	// Kernel is the host kernel version the benchmarks were run on.
+	Kernel string
+	// Runtime is the container runtime (e.g. "runc", "runsc") under test.
+	Runtime string
+	// Metadata is attached to every bigquery.Benchmark read back from the
+	// archive.
+	Metadata *bigquery.Metadata
+}
+
+// SyntaxError reports a single malformed benchmark line encountered by a
+// Reader. Unlike parseLine's fail-fast behavior, encountering a
+// SyntaxError does not invalidate the rest of the archive: callers can
+// inspect it and keep calling Reader.Next to continue past it.
+type SyntaxError struct {
+	// Line is the 1-indexed line number the error occurred on.
+	Line int
+	// Text is the raw line contents.
+	Text string
+	// Err is the underlying parse error.
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("archive: syntax error on line %d: %v", e.Line, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error { return e.Err }
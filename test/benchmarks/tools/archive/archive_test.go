@@ -0,0 +1,180 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+func newBenchmark(name string) *bigquery.Benchmark {
+	bm := bigquery.NewBenchmark(name, 10, false)
+	bm.AddCondition("GOMAXPROCS", "8")
+	bm.AddCondition("workload", "web")
+	bm.AddMetric("ns/op", "ns/op", 123)
+	bm.AddMetric("latency", "ms", 45)
+	return bm
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	header := Header{
+		GitSHA:  "deadbeef",
+		Kernel:  "5.4.0",
+		Runtime: "runsc",
+		Metadata: &bigquery.Metadata{
+			CL:     "12345",
+			Config: map[string]string{"go": "go1.17"},
+		},
+	}
+	if err := w.WriteHeader(header); err != nil {
+		t.Fatalf("WriteHeader returned error: %v", err)
+	}
+	want := []*bigquery.Benchmark{newBenchmark("BenchmarkFoo"), newBenchmark("BenchmarkBar")}
+	for _, bm := range want {
+		if err := w.WriteBenchmark(bm); err != nil {
+			t.Fatalf("WriteBenchmark returned error: %v", err)
+		}
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader returned error: %v", err)
+	}
+	gotHeader := r.Header()
+	if gotHeader.GitSHA != header.GitSHA || gotHeader.Kernel != header.Kernel || gotHeader.Runtime != header.Runtime {
+		t.Errorf("Header() = %+v, want %+v", gotHeader, header)
+	}
+	if gotHeader.Metadata.CL != "12345" || gotHeader.Metadata.Config["go"] != "go1.17" {
+		t.Errorf("Header().Metadata = %+v, want CL=12345 Config[go]=go1.17", gotHeader.Metadata)
+	}
+
+	var got []*bigquery.Benchmark
+	for {
+		bm, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() returned error: %v", err)
+		}
+		got = append(got, bm)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d benchmarks, want %d", len(got), len(want))
+	}
+	for i, bm := range got {
+		if bm.Name != want[i].Name {
+			t.Errorf("got[%d].Name = %q, want %q", i, bm.Name, want[i].Name)
+		}
+		if len(bm.Metric) != 2 || bm.Metric[0].Sample != 123 || bm.Metric[1].Name != "latency" || bm.Metric[1].Unit != "ms" || bm.Metric[1].Sample != 45 {
+			t.Errorf("got[%d].Metric = %+v, want round-tripped ns/op=123 and latency(ms)=45", i, bm.Metric)
+		}
+		if bm.Metadata.CL != "12345" {
+			t.Errorf("got[%d].Metadata.CL = %q, want 12345", i, bm.Metadata.CL)
+		}
+	}
+}
+
+func TestReaderSyntaxErrorContinues(t *testing.T) {
+	input := "\nBenchmarkFoo-8 10 1 ns/op\n" +
+		"BenchmarkBar-nogomaxprocs 10 2 ns/op\n" +
+		"BenchmarkBaz-8 10 3 ns/op\n"
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewReader returned error: %v", err)
+	}
+
+	bm, err := r.Next()
+	if err != nil || bm.Name != "BenchmarkFoo" {
+		t.Fatalf("first Next() = (%+v, %v), want BenchmarkFoo", bm, err)
+	}
+
+	// "BenchmarkBar-nogomaxprocs ..." fails to parse (not numeric
+	// GOMAXPROCS), but the Reader should report it as a *SyntaxError and
+	// be ready to continue from the following line.
+	_, err = r.Next()
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) {
+		t.Fatalf("second Next() error = %v, want a *SyntaxError", err)
+	}
+
+	bm, err = r.Next()
+	if err != nil || bm.Name != "BenchmarkBaz" {
+		t.Fatalf("third Next() = (%+v, %v), want BenchmarkBaz", bm, err)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("final Next() error = %v, want io.EOF", err)
+	}
+}
+
+func TestMergeDeduplicates(t *testing.T) {
+	var a, b bytes.Buffer
+	writeArchive := func(buf *bytes.Buffer, cl string, names ...string) {
+		w := NewWriter(buf)
+		if err := w.WriteHeader(Header{Metadata: &bigquery.Metadata{CL: cl}}); err != nil {
+			t.Fatalf("WriteHeader returned error: %v", err)
+		}
+		for _, name := range names {
+			if err := w.WriteBenchmark(newBenchmark(name)); err != nil {
+				t.Fatalf("WriteBenchmark returned error: %v", err)
+			}
+		}
+	}
+	writeArchive(&a, "111", "BenchmarkFoo", "BenchmarkBar")
+	writeArchive(&b, "111", "BenchmarkFoo", "BenchmarkBaz")
+
+	var merged bytes.Buffer
+	syntaxErrs, err := Merge([]io.Reader{&a, &b}, &merged)
+	if err != nil {
+		t.Fatalf("Merge returned error: %v", err)
+	}
+	if len(syntaxErrs) != 0 {
+		t.Fatalf("Merge returned syntax errors: %v", syntaxErrs)
+	}
+
+	r, err := NewReader(&merged)
+	if err != nil {
+		t.Fatalf("NewReader returned error: %v", err)
+	}
+	var names []string
+	for {
+		bm, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() returned error: %v", err)
+		}
+		names = append(names, bm.Name)
+	}
+	// BenchmarkFoo@111 appears in both archives and should be merged once.
+	want := []string{"BenchmarkFoo", "BenchmarkBar", "BenchmarkBaz"}
+	if len(names) != len(want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	for i, name := range want {
+		if names[i] != name {
+			t.Errorf("names[%d] = %q, want %q", i, names[i], name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command archivemerge concatenates benchmark archives, deduplicates
+// their records, and re-emits a single canonical archive file suitable
+// for feeding into benchstat or a BigQuery uploader.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gvisor.dev/gvisor/test/benchmarks/tools/archive"
+)
+
+func main() {
+	out := flag.String("out", "", "path to write the merged archive to (required)")
+	flag.Parse()
+	if *out == "" || flag.NArg() == 0 {
+		log.Fatalf("usage: archivemerge -out=merged.archive archive1 archive2 ...")
+	}
+	if absOut, err := filepath.Abs(*out); err == nil {
+		for _, path := range flag.Args() {
+			if absPath, err := filepath.Abs(path); err == nil && absPath == absOut {
+				log.Fatalf("-out=%s must not be one of the input archives", *out)
+			}
+		}
+	}
+
+	var srcs []*os.File
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("open %s: %v", path, err)
+		}
+		defer f.Close()
+		srcs = append(srcs, f)
+	}
+
+	dst, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("create %s: %v", *out, err)
+	}
+	defer dst.Close()
+
+	readers := make([]io.Reader, len(srcs))
+	for i, f := range srcs {
+		readers[i] = f
+	}
+	syntaxErrs, err := archive.Merge(readers, dst)
+	for _, se := range syntaxErrs {
+		fmt.Fprintln(os.Stderr, se)
+	}
+	if err != nil {
+		log.Fatalf("merge: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+	"gvisor.dev/gvisor/tools/parsers"
+)
+
+// encodeBenchmark renders bm as a single canonical Go benchmark text line:
+// "BenchmarkName/params-GOMAXPROCS iters value1 unit1 value2 unit2 ...".
+//
+// A Metric aggregated from repeated samples is written as its mean; see
+// the package doc for why the rest of its distribution isn't preserved.
+func encodeBenchmark(bm *bigquery.Benchmark) string {
+	var b strings.Builder
+	b.WriteString(bm.Name)
+
+	gomaxprocs := "1"
+	var params []string
+	for _, c := range bm.Condition {
+		if c.Name == "GOMAXPROCS" {
+			gomaxprocs = c.Value
+			continue
+		}
+		if c.Name == c.Value {
+			params = append(params, c.Value)
+		} else {
+			params = append(params, c.Name+"."+c.Value)
+		}
+	}
+	if len(params) > 0 {
+		b.WriteByte('/')
+		b.WriteString(strings.Join(params, "/"))
+	}
+	b.WriteByte('-')
+	b.WriteString(gomaxprocs)
+
+	fmt.Fprintf(&b, " %d", bm.Iters)
+	for _, m := range bm.Metric {
+		unit := m.Unit
+		if m.Name != m.Unit {
+			unit = m.Name + "." + m.Unit
+		}
+		fmt.Fprintf(&b, " %s %s", formatValue(m.Sample), unit)
+	}
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// decodeBenchmark parses a single canonical Go benchmark text line back
+// into a bigquery.Benchmark.
+func decodeBenchmark(line string) (*bigquery.Benchmark, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("two fields required, got: %d", len(fields))
+	}
+	if !strings.HasPrefix(fields[0], "Benchmark") {
+		return nil, fmt.Errorf("invalid prefix: %s", fields[0])
+	}
+	iters, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("expecting number of runs, got %s: %v", fields[1], err)
+	}
+	name, params, err := parsers.ParseNameParams(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse name/params: %v", err)
+	}
+
+	bm := bigquery.NewBenchmark(name, iters, false)
+	for _, p := range params {
+		bm.AddCondition(p.Name, p.Value)
+	}
+
+	rest := fields[2:]
+	for i := 0; 2*i+1 < len(rest); i++ {
+		value, unit := rest[2*i], rest[2*i+1]
+		sample, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ParseFloat %s: %v", value, err)
+		}
+		name, u := unit, unit
+		if strings.Contains(unit, ".") {
+			parts := strings.SplitN(unit, ".", 2)
+			name, u = parts[0], parts[1]
+		}
+		bm.AddMetric(name, u, sample)
+	}
+	return bm, nil
+}
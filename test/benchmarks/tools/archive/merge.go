@@ -0,0 +1,88 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"errors"
+	"io"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+// Merge concatenates the archives read from srcs into a single canonical
+// archive written to dst, deduplicating records by (name, params,
+// metadata commit) and skipping any line a *SyntaxError was returned for.
+// The merged archive's header is taken from the first source.
+//
+// Merge returns every *SyntaxError it skipped over, alongside any fatal
+// error that stopped the merge early.
+func Merge(srcs []io.Reader, dst io.Writer) ([]*SyntaxError, error) {
+	if len(srcs) == 0 {
+		return nil, errors.New("archive: no sources to merge")
+	}
+
+	w := NewWriter(dst)
+	seen := make(map[string]bool)
+	var syntaxErrs []*SyntaxError
+
+	for i, src := range srcs {
+		r, err := NewReader(src)
+		if err != nil {
+			return syntaxErrs, err
+		}
+		if i == 0 {
+			if err := w.WriteHeader(r.Header()); err != nil {
+				return syntaxErrs, err
+			}
+		}
+		for {
+			bm, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			var synErr *SyntaxError
+			if errors.As(err, &synErr) {
+				syntaxErrs = append(syntaxErrs, synErr)
+				continue
+			}
+			if err != nil {
+				return syntaxErrs, err
+			}
+			key := dedupKey(bm)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if err := w.WriteBenchmark(bm); err != nil {
+				return syntaxErrs, err
+			}
+		}
+	}
+	return syntaxErrs, nil
+}
+
+// dedupKey identifies a Benchmark by its name, parameters, and the commit
+// it was run at, so the same benchmark re-run at the same commit (e.g.
+// because two archives overlap) is only emitted once.
+func dedupKey(bm *bigquery.Benchmark) string {
+	key := bm.Name
+	for _, c := range bm.Condition {
+		key += "/" + c.Name + "=" + c.Value
+	}
+	if bm.Metadata != nil {
+		key += "@" + bm.Metadata.CL
+	}
+	return key
+}
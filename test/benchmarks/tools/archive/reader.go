@@ -0,0 +1,114 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+// headerLineRE matches a header "key: value" line. Unlike the parsers
+// package's configLineRE, keys may contain hyphens (e.g. "git-sha") since
+// archive headers are always file-scope and never indented.
+var headerLineRE = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*): (.*)$`)
+
+// Reader reads the records written by a Writer back out one at a time.
+// Unlike parsers.parseLine, a malformed benchmark line does not abort the
+// read: Next returns a *SyntaxError for that line and the Reader is ready
+// to continue from the line after it, so one corrupt record in a large
+// archive doesn't block replaying the rest.
+type Reader struct {
+	scanner *bufio.Scanner
+	header  Header
+	line    int
+}
+
+// NewReader reads the archive's header section from r and returns a Reader
+// positioned at the first benchmark line.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{scanner: bufio.NewScanner(r)}
+	if err := rd.readHeader(); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// Header returns the archive's header, as read by NewReader.
+func (r *Reader) Header() Header { return r.header }
+
+func (r *Reader) readHeader() error {
+	config := make(map[string]string)
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Text()
+		if line == "" {
+			break
+		}
+		m := headerLineRE.FindStringSubmatch(line)
+		if m == nil {
+			return fmt.Errorf("archive: malformed header line %d: %q", r.line, line)
+		}
+		config[m[1]] = m[2]
+	}
+	if err := r.scanner.Err(); err != nil {
+		return err
+	}
+	md := &bigquery.Metadata{Config: make(map[string]string)}
+	for k, v := range config {
+		switch k {
+		case gitSHAKey:
+			r.header.GitSHA = v
+		case kernelKey:
+			r.header.Kernel = v
+		case runtimeKey:
+			r.header.Runtime = v
+		case clKey:
+			md.CL = v
+		default:
+			md.Config[k] = v
+		}
+	}
+	r.header.Metadata = md
+	return nil
+}
+
+// Next returns the next Benchmark in the archive. It returns io.EOF once
+// the archive is exhausted, or a *SyntaxError if the next line could not
+// be parsed; callers may call Next again after a *SyntaxError to resume
+// from the following line.
+func (r *Reader) Next() (*bigquery.Benchmark, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		bm, err := decodeBenchmark(line)
+		if err != nil {
+			return nil, &SyntaxError{Line: r.line, Text: line, Err: err}
+		}
+		bm.Metadata = r.header.Metadata
+		return bm, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+// Writer persists a stream of bigquery.Benchmark records as an archive
+// file. WriteHeader must be called exactly once, before any call to
+// WriteBenchmark.
+type Writer struct {
+	w           io.Writer
+	wroteHeader bool
+}
+
+// NewWriter returns a Writer that writes an archive to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteHeader writes h's fields once, at the top of the archive, as
+// file-scope "key: value" lines.
+func (w *Writer) WriteHeader(h Header) error {
+	if w.wroteHeader {
+		return fmt.Errorf("archive: header already written")
+	}
+	for _, kv := range [][2]string{
+		{gitSHAKey, h.GitSHA},
+		{kernelKey, h.Kernel},
+		{runtimeKey, h.Runtime},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w.w, "%s: %s\n", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	if h.Metadata != nil {
+		if h.Metadata.CL != "" {
+			if _, err := fmt.Fprintf(w.w, "%s: %s\n", clKey, h.Metadata.CL); err != nil {
+				return err
+			}
+		}
+		keys := make([]string, 0, len(h.Metadata.Config))
+		for k := range h.Metadata.Config {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if _, err := fmt.Fprintf(w.w, "%s: %s\n", k, h.Metadata.Config[k]); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := fmt.Fprintln(w.w); err != nil {
+		return err
+	}
+	w.wroteHeader = true
+	return nil
+}
+
+// WriteBenchmark appends a single bigquery.Benchmark to the archive in
+// canonical Go benchmark text format.
+func (w *Writer) WriteBenchmark(bm *bigquery.Benchmark) error {
+	if !w.wroteHeader {
+		return fmt.Errorf("archive: WriteHeader must be called before WriteBenchmark")
+	}
+	_, err := fmt.Fprintln(w.w, encodeBenchmark(bm))
+	return err
+}
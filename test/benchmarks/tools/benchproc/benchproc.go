@@ -0,0 +1,249 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package benchproc projects and filters parsed benchmark records before
+// they're uploaded to BigQuery, letting callers select which results to
+// upload and how to key them without post-processing in BigQuery itself.
+//
+// A projection is a comma-separated expression such as
+// ".config,GOMAXPROCS,workload@(small medium large)" where each element
+// selects either a fixed key (".fullname", ".config", ".file", ".unit") or
+// a named parameter, optionally with an explicit value order. A filter is a
+// boolean expression over the same keys, e.g.
+// "GOMAXPROCS>=4 AND workload=web". The expression grammar is defined by
+// the syntax subpackage.
+package benchproc
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/test/benchmarks/tools/benchproc/syntax"
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+// Record is the unit a Projection or Filter operates on: one
+// (benchmark, parameter set, metric) observation. Benchmarks report
+// multiple metrics (ns/op, B/op, a custom metric, ...) per run, so
+// ".unit" and a metric's sample value are only meaningful once a
+// Benchmark has been split into its per-metric Records.
+type Record struct {
+	Benchmark *bigquery.Benchmark
+	Metric    *bigquery.Metric
+}
+
+// Split returns one Record per Metric reported by bm.
+func Split(bm *bigquery.Benchmark) []*Record {
+	records := make([]*Record, 0, len(bm.Metric))
+	for _, m := range bm.Metric {
+		records = append(records, &Record{Benchmark: bm, Metric: m})
+	}
+	return records
+}
+
+// Key is an interned tuple produced by a Projection, usable as a Go map
+// key to group Records that share the same projected field values.
+type Key string
+
+// Projection selects and orders a subset of a Record's fields, producing a
+// Key that groups records sharing those field values.
+type Projection struct {
+	syn *syntax.Projection
+}
+
+// NewProjection parses a projection expression.
+func NewProjection(expr string) (*Projection, error) {
+	syn, err := syntax.ParseProjection(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parse projection %q: %v", expr, err)
+	}
+	return &Projection{syn: syn}, nil
+}
+
+// Project returns the Key for r under p. ok is false if r should be
+// excluded, which happens when an element's order was given as
+// "@(fixed)" and r's value for that element isn't in the list.
+func (p *Projection) Project(r *Record) (Key, bool) {
+	parts := make([]string, 0, len(p.syn.Elems))
+	for _, elem := range p.syn.Elems {
+		value, _ := fieldValue(r, elem.Key)
+		if elem.Fixed && !containsStr(elem.Order, value) {
+			return "", false
+		}
+		parts = append(parts, value)
+	}
+	return Key(strings.Join(parts, "\x1f")), true
+}
+
+// Less orders two Keys produced by p, honoring each element's explicit
+// "@(...)" value order where given and falling back to lexical order for
+// the rest.
+func (p *Projection) Less(a, b Key) bool {
+	as := strings.Split(string(a), "\x1f")
+	bs := strings.Split(string(b), "\x1f")
+	for i, elem := range p.syn.Elems {
+		if i >= len(as) || i >= len(bs) {
+			break
+		}
+		av, bv := as[i], bs[i]
+		if av == bv {
+			continue
+		}
+		if len(elem.Order) > 0 {
+			if ai, bi := rankOf(elem.Order, av), rankOf(elem.Order, bv); ai != bi {
+				return ai < bi
+			}
+		}
+		return av < bv
+	}
+	return false
+}
+
+func rankOf(order []string, v string) int {
+	for i, o := range order {
+		if o == v {
+			return i
+		}
+	}
+	return len(order) // Unlisted values sort last.
+}
+
+func containsStr(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter is the parsed form of a filter expression.
+type Filter struct {
+	expr syntax.Expr
+}
+
+// NewFilter parses a filter expression.
+func NewFilter(expr string) (*Filter, error) {
+	syn, err := syntax.ParseFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parse filter %q: %v", expr, err)
+	}
+	return &Filter{expr: syn.Expr}, nil
+}
+
+// Match reports whether r satisfies f.
+func (f *Filter) Match(r *Record) bool {
+	return matchExpr(f.expr, r)
+}
+
+func matchExpr(e syntax.Expr, r *Record) bool {
+	switch e := e.(type) {
+	case *syntax.BinOp:
+		switch e.Op {
+		case "AND":
+			return matchExpr(e.X, r) && matchExpr(e.Y, r)
+		case "OR":
+			return matchExpr(e.X, r) || matchExpr(e.Y, r)
+		}
+		return false
+	case *syntax.NotExpr:
+		return !matchExpr(e.X, r)
+	case *syntax.Compare:
+		return matchCompare(e, r)
+	default:
+		return false
+	}
+}
+
+func matchCompare(c *syntax.Compare, r *Record) bool {
+	value, _ := fieldValue(r, c.Key)
+	switch c.Op {
+	case "=":
+		return value == c.Value
+	case "!=":
+		return value != c.Value
+	case ":":
+		ok, err := path.Match(c.Value, value)
+		return err == nil && ok
+	case ">", ">=", "<", "<=":
+		vf, err1 := strconv.ParseFloat(value, 64)
+		cf, err2 := strconv.ParseFloat(c.Value, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		switch c.Op {
+		case ">":
+			return vf > cf
+		case ">=":
+			return vf >= cf
+		case "<":
+			return vf < cf
+		default: // "<="
+			return vf <= cf
+		}
+	default:
+		return false
+	}
+}
+
+// fieldValue resolves key against r, returning ok=false if r has no value
+// for key (e.g. a parameter r.Benchmark's name didn't include).
+func fieldValue(r *Record, key syntax.Key) (string, bool) {
+	switch key.Name {
+	case ".fullname":
+		return r.Benchmark.Name, true
+	case ".unit":
+		if r.Metric == nil {
+			return "", false
+		}
+		return r.Metric.Unit, true
+	case ".config":
+		return formatConfig(r.Benchmark.Metadata), true
+	case ".file":
+		if r.Benchmark.Metadata != nil {
+			if f, ok := r.Benchmark.Metadata.Config["file"]; ok {
+				return f, true
+			}
+		}
+		return "", false
+	default:
+		for _, c := range r.Benchmark.Condition {
+			if c.Name == key.Name {
+				return c.Value, true
+			}
+		}
+		return "", false
+	}
+}
+
+// formatConfig renders a Metadata's free-form Config map as a stable,
+// sorted "key=value,key=value" string for use as a ".config" field value.
+func formatConfig(md *bigquery.Metadata) string {
+	if md == nil || len(md.Config) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(md.Config))
+	for k := range md.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+md.Config[k])
+	}
+	return strings.Join(parts, ",")
+}
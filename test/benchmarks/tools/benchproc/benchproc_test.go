@@ -0,0 +1,135 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchproc
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+func newBenchmark(name, gomaxprocs, workload string) *bigquery.Benchmark {
+	bm := bigquery.NewBenchmark(name, 10, false)
+	bm.AddCondition("GOMAXPROCS", gomaxprocs)
+	bm.AddCondition("workload", workload)
+	bm.AddMetric("ns/op", "ns/op", 100)
+	return bm
+}
+
+func TestSplit(t *testing.T) {
+	bm := newBenchmark("BenchmarkFoo", "8", "web")
+	bm.AddMetric("B/op", "B/op", 64)
+	records := Split(bm)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0].Metric.Name != "ns/op" || records[1].Metric.Name != "B/op" {
+		t.Errorf("records in unexpected order: %+v", records)
+	}
+	for _, r := range records {
+		if r.Benchmark != bm {
+			t.Errorf("record.Benchmark = %p, want %p", r.Benchmark, bm)
+		}
+	}
+}
+
+func TestProjection(t *testing.T) {
+	proj, err := NewProjection(".fullname,GOMAXPROCS")
+	if err != nil {
+		t.Fatalf("NewProjection returned error: %v", err)
+	}
+	a := Split(newBenchmark("BenchmarkFoo", "8", "web"))[0]
+	b := Split(newBenchmark("BenchmarkFoo", "8", "db"))[0]
+	c := Split(newBenchmark("BenchmarkFoo", "4", "web"))[0]
+
+	keyA, ok := proj.Project(a)
+	if !ok {
+		t.Fatalf("Project(a) ok = false, want true")
+	}
+	keyB, ok := proj.Project(b)
+	if !ok {
+		t.Fatalf("Project(b) ok = false, want true")
+	}
+	if keyA != keyB {
+		t.Errorf("keyA (%q) != keyB (%q), want equal since workload isn't projected", keyA, keyB)
+	}
+	keyC, ok := proj.Project(c)
+	if !ok {
+		t.Fatalf("Project(c) ok = false, want true")
+	}
+	if keyA == keyC {
+		t.Errorf("keyA == keyC (%q), want different since GOMAXPROCS differs", keyA)
+	}
+}
+
+func TestProjectionFixedDropsUnlisted(t *testing.T) {
+	proj, err := NewProjection("workload@(fixed)")
+	if err != nil {
+		t.Fatalf("NewProjection returned error: %v", err)
+	}
+	r := Split(newBenchmark("BenchmarkFoo", "8", "other"))[0]
+	if _, ok := proj.Project(r); ok {
+		t.Errorf("Project(r) ok = true, want false for a value not in the @(fixed) list")
+	}
+}
+
+func TestProjectionLessHonorsExplicitOrder(t *testing.T) {
+	proj, err := NewProjection("workload@(large medium small)")
+	if err != nil {
+		t.Fatalf("NewProjection returned error: %v", err)
+	}
+	large, _ := proj.Project(Split(newBenchmark("BenchmarkFoo", "8", "large"))[0])
+	small, _ := proj.Project(Split(newBenchmark("BenchmarkFoo", "8", "small"))[0])
+	if !proj.Less(large, small) {
+		t.Errorf("Less(large, small) = false, want true: explicit order puts large first")
+	}
+	if proj.Less(small, large) {
+		t.Errorf("Less(small, large) = true, want false")
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	filter, err := NewFilter("GOMAXPROCS>=8 AND workload:web*")
+	if err != nil {
+		t.Fatalf("NewFilter returned error: %v", err)
+	}
+	matches := Split(newBenchmark("BenchmarkFoo", "8", "webserver"))[0]
+	if !filter.Match(matches) {
+		t.Errorf("Match(matches) = false, want true")
+	}
+	wrongProcs := Split(newBenchmark("BenchmarkFoo", "4", "webserver"))[0]
+	if filter.Match(wrongProcs) {
+		t.Errorf("Match(wrongProcs) = true, want false")
+	}
+	wrongWorkload := Split(newBenchmark("BenchmarkFoo", "8", "database"))[0]
+	if filter.Match(wrongWorkload) {
+		t.Errorf("Match(wrongWorkload) = true, want false")
+	}
+}
+
+func TestFilterNot(t *testing.T) {
+	filter, err := NewFilter("NOT workload=web")
+	if err != nil {
+		t.Fatalf("NewFilter returned error: %v", err)
+	}
+	web := Split(newBenchmark("BenchmarkFoo", "8", "web"))[0]
+	db := Split(newBenchmark("BenchmarkFoo", "8", "db"))[0]
+	if filter.Match(web) {
+		t.Errorf("Match(web) = true, want false")
+	}
+	if !filter.Match(db) {
+		t.Errorf("Match(db) = false, want true")
+	}
+}
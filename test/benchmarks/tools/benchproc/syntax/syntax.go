@@ -0,0 +1,250 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package syntax defines the AST and parser for benchproc's projection and
+// filter expressions, kept separate from benchproc itself so the same
+// expressions can later be translated to other targets, such as SQL WHERE
+// clauses, without depending on benchproc's evaluation logic.
+package syntax
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Key identifies a field of a benchmark record: one of the fixed keys
+// (".fullname", ".config", ".file", ".unit") or the name of a benchmark
+// parameter, e.g. "GOMAXPROCS" or "workload".
+type Key struct {
+	Name string
+}
+
+// Fixed reports whether k is one of the built-in keys rather than a
+// benchmark parameter name.
+func (k Key) Fixed() bool { return strings.HasPrefix(k.Name, ".") }
+
+// ProjectionElem is one comma-separated element of a Projection: a Key,
+// plus an optional explicit value order supplied with "@(...)".
+type ProjectionElem struct {
+	Key Key
+	// Order lists the values in display order, as given inside "@(...)".
+	// Values not listed sort after every listed value, unless Fixed is set.
+	Order []string
+	// Fixed is true for the literal suffix "@(fixed)", which leaves Order
+	// empty: values not in Order are dropped rather than sorted last.
+	Fixed bool
+}
+
+// Projection is the parsed form of a projection expression, e.g.
+// ".config,GOMAXPROCS,workload@(small medium large)".
+type Projection struct {
+	Elems []*ProjectionElem
+}
+
+var elemRE = regexp.MustCompile(`^([.\w-]+)(?:@\(([^)]*)\))?$`)
+
+// ParseProjection parses a comma-separated projection expression.
+func ParseProjection(s string) (*Projection, error) {
+	proj := &Projection{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		m := elemRE.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("invalid projection element %q", part)
+		}
+		elem := &ProjectionElem{Key: Key{Name: m[1]}}
+		if order := strings.TrimSpace(m[2]); order != "" {
+			if order == "fixed" {
+				elem.Fixed = true
+			} else {
+				elem.Order = strings.Fields(order)
+			}
+		}
+		proj.Elems = append(proj.Elems, elem)
+	}
+	if len(proj.Elems) == 0 {
+		return nil, fmt.Errorf("empty projection %q", s)
+	}
+	return proj, nil
+}
+
+// Expr is a node in a filter expression's boolean AST.
+type Expr interface {
+	isExpr()
+}
+
+// BinOp is a binary "AND"/"OR" of two sub-expressions.
+type BinOp struct {
+	Op   string // "AND" or "OR"
+	X, Y Expr
+}
+
+// NotExpr negates its sub-expression.
+type NotExpr struct {
+	X Expr
+}
+
+// Compare is a leaf comparison of a Key against a literal Value.
+type Compare struct {
+	Key   Key
+	Op    string // "=", "!=", ">=", "<=", ">", "<", or ":" (glob match)
+	Value string
+}
+
+func (*BinOp) isExpr()   {}
+func (*NotExpr) isExpr() {}
+func (*Compare) isExpr() {}
+
+// Filter is the parsed form of a filter expression, e.g.
+// "GOMAXPROCS>=4 AND workload=web".
+type Filter struct {
+	Expr Expr
+}
+
+var compareRE = regexp.MustCompile(`^([.\w-]+)(!=|>=|<=|=|:|>|<)(.+)$`)
+
+// ParseFilter parses a boolean filter expression built from comparisons
+// joined with AND, OR, and NOT, with parentheses for grouping.
+func ParseFilter(s string) (*Filter, error) {
+	p := &parser{tokens: tokenize(s)}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token %q", p.tokens[p.pos])
+	}
+	return &Filter{Expr: expr}, nil
+}
+
+// tokenize splits a filter expression into parens, keywords, and
+// comparison atoms (e.g. "GOMAXPROCS>=4" is a single token, since
+// comparisons never contain whitespace).
+func tokenize(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &BinOp{Op: "OR", X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &BinOp{Op: "AND", X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if strings.EqualFold(p.peek(), "NOT") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.peek() == "(" {
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing ')'")
+		}
+		return x, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (Expr, error) {
+	tok := p.next()
+	if tok == "" {
+		return nil, fmt.Errorf("unexpected end of filter expression")
+	}
+	m := compareRE.FindStringSubmatch(tok)
+	if m == nil {
+		return nil, fmt.Errorf("invalid comparison %q", tok)
+	}
+	return &Compare{Key: Key{Name: m[1]}, Op: m[2], Value: m[3]}, nil
+}
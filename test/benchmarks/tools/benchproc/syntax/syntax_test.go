@@ -0,0 +1,114 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syntax
+
+import "testing"
+
+func TestParseProjection(t *testing.T) {
+	p, err := ParseProjection(".config,GOMAXPROCS,workload@(small medium large)")
+	if err != nil {
+		t.Fatalf("ParseProjection returned error: %v", err)
+	}
+	if len(p.Elems) != 3 {
+		t.Fatalf("got %d elements, want 3", len(p.Elems))
+	}
+	if p.Elems[0].Key.Name != ".config" || !p.Elems[0].Key.Fixed() {
+		t.Errorf("Elems[0] = %+v, want fixed key .config", p.Elems[0])
+	}
+	if p.Elems[1].Key.Name != "GOMAXPROCS" || p.Elems[1].Key.Fixed() {
+		t.Errorf("Elems[1] = %+v, want non-fixed key GOMAXPROCS", p.Elems[1])
+	}
+	elem := p.Elems[2]
+	if elem.Key.Name != "workload" {
+		t.Errorf("Elems[2].Key = %+v, want workload", elem.Key)
+	}
+	if want := []string{"small", "medium", "large"}; !stringsEqual(elem.Order, want) {
+		t.Errorf("Elems[2].Order = %v, want %v", elem.Order, want)
+	}
+	if elem.Fixed {
+		t.Errorf("Elems[2].Fixed = true, want false")
+	}
+}
+
+func TestParseProjectionFixedOrder(t *testing.T) {
+	p, err := ParseProjection("workload@(fixed)")
+	if err != nil {
+		t.Fatalf("ParseProjection returned error: %v", err)
+	}
+	if len(p.Elems) != 1 || !p.Elems[0].Fixed {
+		t.Fatalf("got %+v, want a single fixed element", p.Elems)
+	}
+}
+
+func TestParseProjectionInvalid(t *testing.T) {
+	if _, err := ParseProjection(""); err == nil {
+		t.Errorf("ParseProjection(\"\") succeeded, want an error")
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("GOMAXPROCS>=4 AND workload=web")
+	if err != nil {
+		t.Fatalf("ParseFilter returned error: %v", err)
+	}
+	bin, ok := f.Expr.(*BinOp)
+	if !ok {
+		t.Fatalf("Expr = %T, want *BinOp", f.Expr)
+	}
+	if bin.Op != "AND" {
+		t.Errorf("Op = %q, want AND", bin.Op)
+	}
+	x, ok := bin.X.(*Compare)
+	if !ok || x.Key.Name != "GOMAXPROCS" || x.Op != ">=" || x.Value != "4" {
+		t.Errorf("X = %+v, want GOMAXPROCS >= 4", bin.X)
+	}
+	y, ok := bin.Y.(*Compare)
+	if !ok || y.Key.Name != "workload" || y.Op != "=" || y.Value != "web" {
+		t.Errorf("Y = %+v, want workload = web", bin.Y)
+	}
+}
+
+func TestParseFilterNot(t *testing.T) {
+	f, err := ParseFilter("NOT workload:web*")
+	if err != nil {
+		t.Fatalf("ParseFilter returned error: %v", err)
+	}
+	not, ok := f.Expr.(*NotExpr)
+	if !ok {
+		t.Fatalf("Expr = %T, want *NotExpr", f.Expr)
+	}
+	cmp, ok := not.X.(*Compare)
+	if !ok || cmp.Key.Name != "workload" || cmp.Op != ":" || cmp.Value != "web*" {
+		t.Errorf("NotExpr.X = %+v, want workload:web*", not.X)
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	if _, err := ParseFilter("workload == web"); err == nil {
+		t.Errorf("ParseFilter accepted an invalid operator, want an error")
+	}
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
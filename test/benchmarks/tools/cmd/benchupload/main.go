@@ -0,0 +1,135 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command benchupload reads Go benchmark output from stdin, optionally
+// filters and projects it with a benchproc expression, and pushes the
+// result to a Prometheus Pushgateway (or prints it, if no gateway is
+// given). This is the "upload path" benchproc projections and filters are
+// meant to be applied from: instead of uploading every result and
+// post-processing in BigQuery, callers can say which results they want
+// and how to key them up front.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"gvisor.dev/gvisor/test/benchmarks/tools/benchproc"
+	"gvisor.dev/gvisor/tools/bigquery"
+	"gvisor.dev/gvisor/tools/parsers"
+)
+
+func main() {
+	projectExpr := flag.String("project", "", `benchproc projection selecting and ordering output keys, e.g. ".fullname,GOMAXPROCS"; when set, only the last result seen for each key is kept`)
+	filterExpr := flag.String("filter", "", `benchproc filter selecting which results to keep, e.g. "GOMAXPROCS>=4 AND workload=web"`)
+	pushgateway := flag.String("pushgateway", "", "Prometheus Pushgateway URL to push results to; if empty, results are printed to stdout")
+	job := flag.String("job", "gvisor-benchmarks", "Pushgateway job name")
+	flag.Parse()
+
+	var filter *benchproc.Filter
+	if *filterExpr != "" {
+		f, err := benchproc.NewFilter(*filterExpr)
+		if err != nil {
+			log.Fatalf("parse filter: %v", err)
+		}
+		filter = f
+	}
+	var proj *benchproc.Projection
+	if *projectExpr != "" {
+		p, err := benchproc.NewProjection(*projectExpr)
+		if err != nil {
+			log.Fatalf("parse project: %v", err)
+		}
+		proj = p
+	}
+
+	out, err := selectBenchmarks(bufio.NewReader(os.Stdin), filter, proj)
+	if err != nil {
+		log.Fatalf("decode: %v", err)
+	}
+
+	if *pushgateway != "" {
+		if err := parsers.PushGateway(*pushgateway, *job).Push(out); err != nil {
+			log.Fatalf("push: %v", err)
+		}
+		return
+	}
+	if err := parsers.ToPrometheus(out, os.Stdout); err != nil {
+		log.Fatalf("render: %v", err)
+	}
+}
+
+// selectBenchmarks decodes Go benchmark text from r, keeping only the
+// Benchmarks with at least one Record matching filter (if given), and,
+// if proj is given, keeping only the most recent Benchmark seen for each
+// projected Key, ordered by proj.Less.
+func selectBenchmarks(r io.Reader, filter *benchproc.Filter, proj *benchproc.Projection) ([]*bigquery.Benchmark, error) {
+	dec := parsers.NewDecoder(r, &bigquery.Metadata{}, false)
+
+	var unkeyed []*bigquery.Benchmark
+	kept := make(map[benchproc.Key]*bigquery.Benchmark)
+	var order []benchproc.Key
+
+	for {
+		bm, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		records := benchproc.Split(bm)
+		if filter != nil && !anyMatch(filter, records) {
+			continue
+		}
+		if proj == nil {
+			unkeyed = append(unkeyed, bm)
+			continue
+		}
+		for _, r := range records {
+			key, ok := proj.Project(r)
+			if !ok {
+				continue
+			}
+			if _, ok := kept[key]; !ok {
+				order = append(order, key)
+			}
+			kept[key] = bm
+		}
+	}
+
+	if proj == nil {
+		return unkeyed, nil
+	}
+	sort.Slice(order, func(i, j int) bool { return proj.Less(order[i], order[j]) })
+	out := make([]*bigquery.Benchmark, 0, len(order))
+	for _, key := range order {
+		out = append(out, kept[key])
+	}
+	return out, nil
+}
+
+func anyMatch(filter *benchproc.Filter, records []*benchproc.Record) bool {
+	for _, r := range records {
+		if filter.Match(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -49,19 +49,29 @@ func ParametersToName(b *testing.B, params []Parameter) string {
 }
 
 // NameToParameters parses the string created by ParametersToName and returns
-// it as a set of Parameters.
+// it as a set of Parameters. Each "/"-separated value may be a bare
+// positional value, or a "name.value" pair as produced by ParametersToName.
+// For interoperability with third-party Go benchfmt producers, "name=value"
+// is also accepted as a separator.
 func NameToParameters(name string) ([]*Parameter, error) {
 	var params []*Parameter
 	conds := strings.Split(name, "/")
 	for _, cond := range conds {
-		cs := strings.Split(cond, ".")
-		if len(cs) == 1 {
+		var cs []string
+		switch {
+		case strings.Contains(cond, "."):
+			// "." keeps its original all-or-nothing split: a value with more
+			// than one "." isn't a "name.value" pair, it's malformed.
+			cs = strings.Split(cond, ".")
+			if len(cs) != 2 {
+				return nil, fmt.Errorf("failed to parse param: %s", cond)
+			}
+		case strings.Contains(cond, "="):
+			cs = strings.SplitN(cond, "=", 2)
+		default:
 			params = append(params, &Parameter{Name: cond, Value: cond})
 			continue
 		}
-		if len(cs) != 2 {
-			return nil, fmt.Errorf("failed to parse param: %s", cond)
-		}
 		params = append(params, &Parameter{Name: cs[0], Value: cs[1]})
 	}
 	return params, nil
@@ -0,0 +1,49 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import "testing"
+
+func TestNameToParametersDotPair(t *testing.T) {
+	params, err := NameToParameters("size.small/8")
+	if err != nil {
+		t.Fatalf("NameToParameters returned error: %v", err)
+	}
+	if len(params) != 2 {
+		t.Fatalf("got %d params, want 2", len(params))
+	}
+	if params[0].Name != "size" || params[0].Value != "small" {
+		t.Errorf("params[0] = %+v, want {size small}", params[0])
+	}
+	if params[1].Name != "8" || params[1].Value != "8" {
+		t.Errorf("params[1] = %+v, want {8 8}", params[1])
+	}
+}
+
+func TestNameToParametersEqualsPair(t *testing.T) {
+	params, err := NameToParameters("size=small")
+	if err != nil {
+		t.Fatalf("NameToParameters returned error: %v", err)
+	}
+	if len(params) != 1 || params[0].Name != "size" || params[0].Value != "small" {
+		t.Fatalf("got %+v, want a single {size small} param", params)
+	}
+}
+
+func TestNameToParametersRejectsExtraDots(t *testing.T) {
+	if _, err := NameToParameters("a.b.c"); err == nil {
+		t.Errorf("NameToParameters(%q) succeeded, want an error for more than one '.'", "a.b.c")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bigquery holds the structs uploaded to BigQuery by the benchmark
+// pipeline, along with the helpers used to build them up incrementally as
+// parsers consume benchmark tool output.
+package bigquery
+
+// Metadata holds data about the run environment that is common to every
+// Benchmark produced from a single parse, e.g. the commit under test or any
+// file-scope configuration emitted by the benchmark tool itself.
+type Metadata struct {
+	// CL is the changelist or commit hash under test, if known.
+	CL string
+	// Config holds arbitrary file-scope "key: value" configuration lines
+	// reported by the benchmark tool (e.g. a benchfmt "go: go1.17" line)
+	// that don't otherwise have a dedicated field here.
+	Config map[string]string
+}
+
+// Condition is a name/value pair describing how a Benchmark was run, e.g. a
+// benchmark sub-test parameter or GOMAXPROCS.
+type Condition struct {
+	Name  string
+	Value string
+}
+
+// Metric is a single named measurement taken during a benchmark run. When
+// the same (benchmark, params, metric) tuple is observed more than once,
+// e.g. from a "go test -count=N" run, Sample holds the mean of the
+// repeated observations and Samples holds a summary of them; for a
+// single observation, Samples is nil and Sample holds that one value.
+type Metric struct {
+	Name    string
+	Unit    string
+	Sample  float64
+	Samples *MetricSamples
+}
+
+// MetricSamples summarizes repeated observations of the same metric,
+// computed incrementally with a streaming quantile estimator so the raw
+// samples don't need to be retained in full.
+type MetricSamples struct {
+	Count  int
+	Mean   float64
+	Stddev float64
+	P50    float64
+	P90    float64
+	P99    float64
+}
+
+// Benchmark holds a single benchmark result, formatted for upload to
+// BigQuery.
+type Benchmark struct {
+	Name      string
+	Official  bool
+	Iters     int
+	Metric    []*Metric
+	Condition []*Condition
+	Metadata  *Metadata
+}
+
+// NewBenchmark creates a Benchmark with the given name, iteration count, and
+// official flag. Metrics and conditions are added afterwards with AddMetric
+// and AddCondition.
+func NewBenchmark(name string, iters int, official bool) *Benchmark {
+	return &Benchmark{
+		Name:     name,
+		Official: official,
+		Iters:    iters,
+	}
+}
+
+// AddMetric appends a Metric to the Benchmark.
+func (bm *Benchmark) AddMetric(name, unit string, sample float64) {
+	bm.Metric = append(bm.Metric, &Metric{
+		Name:   name,
+		Unit:   unit,
+		Sample: sample,
+	})
+}
+
+// AddMetricSamples appends a Metric aggregated from repeated samples of
+// the same (benchmark, params, metric) tuple.
+func (bm *Benchmark) AddMetricSamples(name, unit string, samples *MetricSamples) {
+	bm.Metric = append(bm.Metric, &Metric{
+		Name:    name,
+		Unit:    unit,
+		Sample:  samples.Mean,
+		Samples: samples,
+	})
+}
+
+// AddCondition appends a Condition to the Benchmark.
+func (bm *Benchmark) AddCondition(name, value string) {
+	bm.Condition = append(bm.Condition, &Condition{
+		Name:  name,
+		Value: value,
+	})
+}
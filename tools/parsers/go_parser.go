@@ -19,28 +19,241 @@
 package parsers
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"gvisor.dev/gvisor/test/benchmarks/tools"
 	"gvisor.dev/gvisor/tools/bigquery"
+	"gvisor.dev/gvisor/tools/parsers/quantile"
 )
 
-// parseOutput expects golang benchmark output returns a Benchmark struct formated for BigQuery.
-func parseOutput(output string, metadata *bigquery.Metadata, official bool) ([]*bigquery.Benchmark, error) {
-	var benchmarks []*bigquery.Benchmark
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if isBenchmark(line) {
-			bm, err := parseLine(line, metadata, official)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse line '%s': %v", line, err)
+// quantileEps bounds the rank error of the quantiles reported in
+// bigquery.MetricSamples, e.g. 0.01 keeps every quantile query within 1%
+// of the true rank.
+const quantileEps = 0.01
+
+// configLineRE matches a benchfmt configuration line: an identifier key
+// followed by ": " and a free-form value, as defined by
+// golang.org/x/perf/benchfmt. A line with no leading whitespace is "file"
+// scope and applies to every benchmark line that follows until overridden;
+// an indented line is "record" scope and applies only to the single
+// benchmark line that immediately follows it.
+var configLineRE = regexp.MustCompile(`^(\s*)([a-zA-Z][a-zA-Z0-9]*): (.*)$`)
+
+// parsedLine is a single parsed benchmark line, buffered so a Decoder can
+// look one line ahead to tell whether it belongs to the same repeated
+// sample group as the line before it.
+type parsedLine struct {
+	fullname     string
+	name         string
+	params       []*tools.Parameter
+	iters        int
+	metrics      []rawMetric
+	fileConfig   map[string]string
+	recordConfig map[string]string
+}
+
+// rawMetric is a single value/unit pair read off a benchmark line, not yet
+// classified as a plain bigquery.Metric or folded into a MetricSamples
+// aggregate.
+type rawMetric struct {
+	value float64
+	name  string
+	unit  string
+}
+
+// Decoder reads benchmarks in the Go benchfmt text format (see
+// golang.org/x/perf/benchfmt) one at a time, so very long `go test -bench`
+// runs can be uploaded as they are produced instead of buffered whole.
+// This accepts output from any tool that emits standard Go benchmark text,
+// not just `go test -bench`, so benchmark files produced by perflock,
+// benchstat, or custom harnesses can be replayed losslessly.
+//
+// When the same (benchmark, params, metric) tuple appears on consecutive
+// lines, e.g. from a "go test -count=N" run, Decoder folds the repeated
+// observations into a single bigquery.MetricSamples rather than emitting
+// one Benchmark per line.
+type Decoder struct {
+	scanner  *bufio.Scanner
+	metadata *bigquery.Metadata
+	official bool
+
+	fileConfig   map[string]string
+	recordConfig map[string]string
+	pending      *parsedLine
+	done         bool
+}
+
+// NewDecoder returns a Decoder that reads benchmarks from r.
+func NewDecoder(r io.Reader, metadata *bigquery.Metadata, official bool) *Decoder {
+	return &Decoder{
+		scanner:      bufio.NewScanner(r),
+		metadata:     metadata,
+		official:     official,
+		fileConfig:   make(map[string]string),
+		recordConfig: make(map[string]string),
+	}
+}
+
+// Next returns the next Benchmark, folding together any immediately
+// repeated samples of the same benchmark. It returns io.EOF once the
+// underlying reader is exhausted.
+func (d *Decoder) Next() (*bigquery.Benchmark, error) {
+	first := d.pending
+	d.pending = nil
+	if first == nil {
+		var err error
+		first, err = d.nextLine()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accs := newMetricAccs(first.metrics)
+	for {
+		line, err := d.nextLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line.fullname != first.fullname {
+			d.pending = line
+			break
+		}
+		accs.add(line.metrics)
+	}
+
+	bm := bigquery.NewBenchmark(first.name, first.iters, d.official)
+	bm.Metadata = mergeMetadata(d.metadata, first.fileConfig)
+	for _, p := range first.params {
+		bm.AddCondition(p.Name, p.Value)
+	}
+	for key, value := range first.recordConfig {
+		bm.AddCondition(key, value)
+	}
+	accs.addTo(bm)
+	return bm, nil
+}
+
+// nextLine scans forward to the next benchmark line, tracking file- and
+// record-scope configuration lines along the way, and parses it.
+func (d *Decoder) nextLine() (*parsedLine, error) {
+	for d.scanner.Scan() {
+		line := d.scanner.Text()
+		if m := configLineRE.FindStringSubmatch(line); m != nil {
+			indent, key, value := m[1], m[2], m[3]
+			if indent == "" {
+				d.fileConfig[key] = value
+			} else {
+				d.recordConfig[key] = value
 			}
-			benchmarks = append(benchmarks, bm)
+			continue
+		}
+		if !isBenchmark(line) {
+			continue
+		}
+		pl, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line '%s': %v", line, err)
+		}
+		// Snapshot file-scope configuration: Next reads one line ahead, and a
+		// later override must not change the config of this line.
+		pl.fileConfig = make(map[string]string, len(d.fileConfig))
+		for k, v := range d.fileConfig {
+			pl.fileConfig[k] = v
+		}
+		pl.recordConfig = d.recordConfig
+		// Record-scope configuration only applies to the single benchmark
+		// line it precedes; reset it now that line has been consumed.
+		d.recordConfig = make(map[string]string)
+		return pl, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	d.done = true
+	return nil, io.EOF
+}
+
+// metricAccs accumulates the rawMetrics seen for every metric across the
+// lines that make up one repeated-sample group, keyed by metric name and
+// unit.
+type metricAccs struct {
+	order []string
+	byKey map[string]*metricAcc
+}
+
+// metricAcc incrementally aggregates repeated observations of a single
+// metric using Welford's algorithm for the running mean and variance, and
+// quantile.Stream for the p50/p90/p99 estimates.
+type metricAcc struct {
+	name   string
+	unit   string
+	count  int
+	mean   float64
+	m2     float64
+	stream *quantile.Stream
+}
+
+func newMetricAccs(metrics []rawMetric) *metricAccs {
+	accs := &metricAccs{byKey: make(map[string]*metricAcc)}
+	accs.add(metrics)
+	return accs
+}
+
+func (accs *metricAccs) add(metrics []rawMetric) {
+	for _, m := range metrics {
+		key := m.name + "\x00" + m.unit
+		acc, ok := accs.byKey[key]
+		if !ok {
+			acc = &metricAcc{name: m.name, unit: m.unit, stream: quantile.New(quantileEps)}
+			accs.byKey[key] = acc
+			accs.order = append(accs.order, key)
+		}
+		acc.insert(m.value)
+	}
+}
+
+func (acc *metricAcc) insert(v float64) {
+	acc.count++
+	delta := v - acc.mean
+	acc.mean += delta / float64(acc.count)
+	acc.m2 += delta * (v - acc.mean)
+	acc.stream.Insert(v)
+}
+
+func (acc *metricAcc) stddev() float64 {
+	if acc.count < 2 {
+		return 0
+	}
+	return math.Sqrt(acc.m2 / float64(acc.count-1))
+}
+
+// addTo adds every accumulated metric to bm, as a plain Metric if it was
+// observed exactly once, or as a MetricSamples aggregate otherwise.
+func (accs *metricAccs) addTo(bm *bigquery.Benchmark) {
+	for _, key := range accs.order {
+		acc := accs.byKey[key]
+		if acc.count == 1 {
+			bm.AddMetric(acc.name, acc.unit, acc.mean)
+			continue
 		}
+		bm.AddMetricSamples(acc.name, acc.unit, &bigquery.MetricSamples{
+			Count:  acc.count,
+			Mean:   acc.mean,
+			Stddev: acc.stddev(),
+			P50:    acc.stream.Query(0.50),
+			P90:    acc.stream.Query(0.90),
+			P99:    acc.stream.Query(0.99),
+		})
 	}
-	return benchmarks, nil
 }
 
 // isBenchmark checks that a line is a benchmark line with metrics.
@@ -58,8 +271,10 @@ func isBenchmark(line string) bool {
 	return true
 }
 
-// parseLine handles parsing a benchmark line into a bigquery.Benchmark.
-func parseLine(line string, metadata *bigquery.Metadata, official bool) (*bigquery.Benchmark, error) {
+// parseLine parses a benchmark line's name, params, iteration count, and
+// raw value/unit pairs. It does not attach configuration or metadata; the
+// caller (Decoder.nextLine) fills those in from the surrounding context.
+func parseLine(line string) (*parsedLine, error) {
 	fields := strings.Fields(line)
 	if len(fields) < 2 {
 		return nil, fmt.Errorf("two fields required, got: %d", len(fields))
@@ -74,32 +289,54 @@ func parseLine(line string, metadata *bigquery.Metadata, official bool) (*bigque
 		return nil, fmt.Errorf("expecting number of runs, got %s: %v", fields[1], err)
 	}
 
-	name, params, err := parseNameParams(fields[0])
+	name, params, err := ParseNameParams(fields[0])
 	if err != nil {
 		return nil, fmt.Errorf("parse name/params: %v", err)
 	}
 
-	bm := bigquery.NewBenchmark(name, iters, official)
-	bm.Metadata = metadata
-	for _, p := range params {
-		bm.AddCondition(p.Name, p.Value)
+	pl := &parsedLine{
+		fullname: fields[0],
+		name:     name,
+		params:   params,
+		iters:    iters,
 	}
-
 	for i := 1; i < len(fields)/2; i++ {
 		value := fields[2*i]
-		metric := fields[2*i+1]
-		if err := makeMetric(bm, value, metric); err != nil {
-			return nil, fmt.Errorf("failed on metric %s value: %s:%v", metric, value, err)
+		unit := fields[2*i+1]
+		m, err := parseMetric(value, unit)
+		if err != nil {
+			return nil, fmt.Errorf("failed on metric %s value: %s:%v", unit, value, err)
 		}
+		pl.metrics = append(pl.metrics, m)
 	}
-	return bm, nil
+	return pl, nil
 }
 
-// parseNameParams parses the Name, GOMAXPROCS, and Params from the test.
+// mergeMetadata returns a copy of base with fileConfig layered on top, so
+// that per-parse file-scope configuration doesn't leak between calls that
+// share the same base Metadata.
+func mergeMetadata(base *bigquery.Metadata, fileConfig map[string]string) *bigquery.Metadata {
+	md := &bigquery.Metadata{Config: make(map[string]string)}
+	if base != nil {
+		md.CL = base.CL
+		for k, v := range base.Config {
+			md.Config[k] = v
+		}
+	}
+	for k, v := range fileConfig {
+		md.Config[k] = v
+	}
+	return md
+}
+
+// ParseNameParams parses the Name, GOMAXPROCS, and Params from the test.
 // field here should be of the format TESTNAME/PARAMS-GOMAXPROCS.
 // Parameters will be separated by a "/" with individual params being
-// "name.value".
-func parseNameParams(field string) (string, []*tools.Parameter, error) {
+// either a positional value, a "name.value" pair, or a "name=value" pair.
+//
+// Exported so other packages that read or write this format, such as
+// archive, don't need to reimplement it.
+func ParseNameParams(field string) (string, []*tools.Parameter, error) {
 	var params []*tools.Parameter
 	// Remove GOMAXPROCS from end.
 	maxIndex := strings.LastIndex(field, "-")
@@ -107,6 +344,9 @@ func parseNameParams(field string) (string, []*tools.Parameter, error) {
 		return "", nil, fmt.Errorf("GOMAXPROCS not found %s", field)
 	}
 	maxProcs := field[maxIndex+1:]
+	if _, err := strconv.Atoi(maxProcs); err != nil {
+		return "", nil, fmt.Errorf("invalid GOMAXPROCS %q in %s: %v", maxProcs, field, err)
+	}
 	params = append(params, &tools.Parameter{
 		Name:  "GOMAXPROCS",
 		Value: maxProcs,
@@ -129,26 +369,22 @@ func parseNameParams(field string) (string, []*tools.Parameter, error) {
 	return name, params, nil
 }
 
-// makeMetric parses metrics and adds them to the passed Benchmark.
-func makeMetric(bm *bigquery.Benchmark, value, metric string) error {
-	switch metric {
-	// Ignore most output from golang benchmarks.
-	case "MB/s":
-	case "B/op":
-	case "allocs/op":
-		return nil
-	case "ns/op":
-		val, err := strconv.ParseFloat(value, 64)
+// parseMetric parses a value/unit pair into a rawMetric. Custom metrics
+// reported via tools.ReportCustomMetric encode their name and unit as
+// "name.unit"; every other unit is a standard Go benchmark unit token
+// (ns/op, sec/op, B/op, allocs/op, L1-miss/op, ...) which is kept whole as
+// both the metric's name and unit rather than special-cased by suffix.
+func parseMetric(value, unit string) (rawMetric, error) {
+	if strings.Contains(unit, ".") {
+		m, err := tools.ParseCustomMetric(value, unit)
 		if err != nil {
-			return fmt.Errorf("ParseFloat %s: %v", value, err)
+			return rawMetric{}, fmt.Errorf("failed to parse custom metric %s: %v", unit, err)
 		}
-		bm.AddMetric(metric /*metric name*/, metric /*unit*/, val /*sample*/)
-	default:
-		m, err := tools.ParseCustomMetric(value, metric)
-		if err != nil {
-			return fmt.Errorf("failed to parse custom metric %s: %v ", metric, err)
-		}
-		bm.AddMetric(m.Name, m.Unit, m.Sample)
+		return rawMetric{value: m.Sample, name: m.Name, unit: m.Unit}, nil
+	}
+	sample, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return rawMetric{}, fmt.Errorf("ParseFloat %s: %v", value, err)
 	}
-	return nil
+	return rawMetric{value: sample, name: unit, unit: unit}, nil
 }
@@ -0,0 +1,158 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsers
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+func decodeAll(t *testing.T, input string) []*bigquery.Benchmark {
+	t.Helper()
+	dec := NewDecoder(strings.NewReader(input), &bigquery.Metadata{CL: "deadbeef"}, true)
+	var out []*bigquery.Benchmark
+	for {
+		bm, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() returned error: %v", err)
+		}
+		out = append(out, bm)
+	}
+	return out
+}
+
+func TestDecoderBasic(t *testing.T) {
+	bms := decodeAll(t, "BenchmarkFoo/size.small-8 100 123 ns/op 456 B/op\n")
+	if len(bms) != 1 {
+		t.Fatalf("got %d benchmarks, want 1", len(bms))
+	}
+	bm := bms[0]
+	if bm.Name != "BenchmarkFoo" {
+		t.Errorf("Name = %q, want BenchmarkFoo", bm.Name)
+	}
+	if bm.Iters != 100 {
+		t.Errorf("Iters = %d, want 100", bm.Iters)
+	}
+	if !bm.Official {
+		t.Errorf("Official = false, want true")
+	}
+	if bm.Metadata == nil || bm.Metadata.CL != "deadbeef" {
+		t.Errorf("Metadata.CL = %+v, want deadbeef", bm.Metadata)
+	}
+	wantConds := map[string]string{"GOMAXPROCS": "8", "size": "small"}
+	if len(bm.Condition) != len(wantConds) {
+		t.Fatalf("got %d conditions, want %d", len(bm.Condition), len(wantConds))
+	}
+	for _, c := range bm.Condition {
+		if want, ok := wantConds[c.Name]; !ok || want != c.Value {
+			t.Errorf("unexpected condition %s=%s", c.Name, c.Value)
+		}
+	}
+	if len(bm.Metric) != 2 {
+		t.Fatalf("got %d metrics, want 2", len(bm.Metric))
+	}
+	if bm.Metric[0].Name != "ns/op" || bm.Metric[0].Sample != 123 {
+		t.Errorf("Metric[0] = %+v, want {ns/op ns/op 123}", bm.Metric[0])
+	}
+	if bm.Metric[1].Name != "B/op" || bm.Metric[1].Sample != 456 {
+		t.Errorf("Metric[1] = %+v, want {B/op B/op 456}", bm.Metric[1])
+	}
+}
+
+func TestDecoderCustomMetric(t *testing.T) {
+	bms := decodeAll(t, "BenchmarkFoo-8 10 789 latency.ms\n")
+	if len(bms) != 1 || len(bms[0].Metric) != 1 {
+		t.Fatalf("got %+v, want one benchmark with one metric", bms)
+	}
+	m := bms[0].Metric[0]
+	if m.Name != "latency" || m.Unit != "ms" || m.Sample != 789 {
+		t.Errorf("Metric = %+v, want {latency ms 789}", m)
+	}
+}
+
+func TestDecoderConfigLines(t *testing.T) {
+	input := "go: go1.17\n" +
+		"  trial: 1\n" +
+		"BenchmarkFoo-8 10 1 ns/op\n" +
+		"BenchmarkBar-8 10 2 ns/op\n"
+	bms := decodeAll(t, input)
+	if len(bms) != 2 {
+		t.Fatalf("got %d benchmarks, want 2", len(bms))
+	}
+	// File-scope config applies to every benchmark that follows.
+	for _, bm := range bms {
+		if got := bm.Metadata.Config["go"]; got != "go1.17" {
+			t.Errorf("%s: Metadata.Config[go] = %q, want go1.17", bm.Name, got)
+		}
+	}
+	// Record-scope config only applies to the single line it precedes.
+	if got := condValue(bms[0], "trial"); got != "1" {
+		t.Errorf("BenchmarkFoo: trial condition = %q, want 1", got)
+	}
+	if got := condValue(bms[1], "trial"); got != "" {
+		t.Errorf("BenchmarkBar: trial condition = %q, want empty", got)
+	}
+}
+
+func condValue(bm *bigquery.Benchmark, name string) string {
+	for _, c := range bm.Condition {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestDecoderAggregatesRepeatedSamples(t *testing.T) {
+	input := "BenchmarkFoo-8 10 100 ns/op\n" +
+		"BenchmarkFoo-8 10 200 ns/op\n" +
+		"BenchmarkFoo-8 10 300 ns/op\n" +
+		"BenchmarkBar-8 10 1 ns/op\n"
+	bms := decodeAll(t, input)
+	if len(bms) != 2 {
+		t.Fatalf("got %d benchmarks, want 2 (repeated Foo samples folded into one)", len(bms))
+	}
+	foo := bms[0]
+	if len(foo.Metric) != 1 {
+		t.Fatalf("got %d metrics for Foo, want 1", len(foo.Metric))
+	}
+	m := foo.Metric[0]
+	if m.Samples == nil {
+		t.Fatalf("Metric.Samples = nil, want aggregated samples")
+	}
+	if m.Samples.Count != 3 {
+		t.Errorf("Samples.Count = %d, want 3", m.Samples.Count)
+	}
+	if m.Samples.Mean != 200 {
+		t.Errorf("Samples.Mean = %v, want 200", m.Samples.Mean)
+	}
+	bar := bms[1]
+	if bar.Metric[0].Samples != nil {
+		t.Errorf("Bar's single sample has Samples set, want nil")
+	}
+}
+
+func TestDecoderMalformedLine(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("BenchmarkFoo-8 10 notanumber ns/op\n"), nil, false)
+	if _, err := dec.Next(); err == nil {
+		t.Fatalf("Next() succeeded on a malformed line, want an error")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+// invalidPromCharRE matches characters not allowed in a Prometheus metric
+// or label name ([a-zA-Z_:][a-zA-Z0-9_:]*).
+var invalidPromCharRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// promName sanitizes name into a valid Prometheus metric or label name.
+func promName(name string) string {
+	name = invalidPromCharRE.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// promQuantiles are the MetricSamples quantiles ToPrometheus exposes on a
+// summary metric, as the Prometheus "quantile" label value they're
+// reported under.
+var promQuantiles = []struct {
+	label string
+	value func(*bigquery.MetricSamples) float64
+}{
+	{"0.5", func(s *bigquery.MetricSamples) float64 { return s.P50 }},
+	{"0.9", func(s *bigquery.MetricSamples) float64 { return s.P90 }},
+	{"0.99", func(s *bigquery.MetricSamples) float64 { return s.P99 }},
+}
+
+// ToPrometheus renders benchmarks in the Prometheus text exposition
+// format, with the benchmark name, every parameter (including
+// GOMAXPROCS), and metadata field emitted as labels. A plain Metric is
+// rendered as one "# TYPE gauge" sample; a Metric aggregated from
+// repeated samples (Samples != nil) is rendered as a "# TYPE summary"
+// with its p50/p90/p99 quantiles plus "_sum"/"_count" series, following
+// Prometheus's standard summary convention, and its standard deviation as
+// a sibling "_stddev" gauge.
+func ToPrometheus(benchmarks []*bigquery.Benchmark, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	seen := make(map[string]bool)
+	for _, bm := range benchmarks {
+		labels := promLabels(bm)
+		for _, m := range bm.Metric {
+			metric := promName(m.Name)
+			if m.Samples == nil {
+				if !seen[metric] {
+					seen[metric] = true
+					fmt.Fprintf(bw, "# HELP %s %s, in %s.\n", metric, m.Name, m.Unit)
+					fmt.Fprintf(bw, "# TYPE %s gauge\n", metric)
+				}
+				fmt.Fprintf(bw, "%s{%s} %v\n", metric, labels, m.Sample)
+				continue
+			}
+			if !seen[metric] {
+				seen[metric] = true
+				fmt.Fprintf(bw, "# HELP %s %s, in %s.\n", metric, m.Name, m.Unit)
+				fmt.Fprintf(bw, "# TYPE %s summary\n", metric)
+			}
+			for _, q := range promQuantiles {
+				fmt.Fprintf(bw, "%s{%s,quantile=\"%s\"} %v\n", metric, labels, q.label, q.value(m.Samples))
+			}
+			fmt.Fprintf(bw, "%s_sum{%s} %v\n", metric, labels, m.Samples.Mean*float64(m.Samples.Count))
+			fmt.Fprintf(bw, "%s_count{%s} %v\n", metric, labels, m.Samples.Count)
+
+			stddevMetric := metric + "_stddev"
+			if !seen[stddevMetric] {
+				seen[stddevMetric] = true
+				fmt.Fprintf(bw, "# HELP %s standard deviation of %s, in %s.\n", stddevMetric, m.Name, m.Unit)
+				fmt.Fprintf(bw, "# TYPE %s gauge\n", stddevMetric)
+			}
+			fmt.Fprintf(bw, "%s{%s} %v\n", stddevMetric, labels, m.Samples.Stddev)
+		}
+	}
+	return bw.Flush()
+}
+
+// promLabels renders bm's name, conditions, and metadata as a sorted
+// Prometheus label set, e.g. `GOMAXPROCS="8",name="BenchmarkFoo"`.
+func promLabels(bm *bigquery.Benchmark) string {
+	labels := map[string]string{"name": bm.Name}
+	for _, c := range bm.Condition {
+		labels[promName(c.Name)] = c.Value
+	}
+	if bm.Metadata != nil {
+		if bm.Metadata.CL != "" {
+			labels["cl"] = bm.Metadata.CL
+		}
+		for k, v := range bm.Metadata.Config {
+			labels[promName(k)] = v
+		}
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Sink is a destination that parsed benchmarks can be uploaded to, such as
+// BigQuery or a Prometheus Pushgateway.
+type Sink interface {
+	Push(benchmarks []*bigquery.Benchmark) error
+}
+
+// PushGateway returns a Sink that pushes benchmarks, rendered with
+// ToPrometheus, to a Prometheus Pushgateway at url under the given job
+// name. This gives CI runs a lightweight, self-hostable path for tracking
+// benchmark results over time in addition to, or instead of, BigQuery.
+func PushGateway(url, job string) Sink {
+	return &pushGatewaySink{url: url, job: job}
+}
+
+type pushGatewaySink struct {
+	url string
+	job string
+}
+
+// Push implements Sink.Push.
+func (s *pushGatewaySink) Push(benchmarks []*bigquery.Benchmark) error {
+	var buf bytes.Buffer
+	if err := ToPrometheus(benchmarks, &buf); err != nil {
+		return fmt.Errorf("render prometheus metrics: %v", err)
+	}
+	endpoint := fmt.Sprintf("%s/metrics/job/%s", strings.TrimRight(s.url, "/"), s.job)
+	resp, err := http.Post(endpoint, "text/plain; version=0.0.4", &buf)
+	if err != nil {
+		return fmt.Errorf("push to %s: %v", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("push to %s: unexpected status %s", endpoint, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsers
+
+import (
+	"bytes"
+	"testing"
+
+	"gvisor.dev/gvisor/tools/bigquery"
+)
+
+func TestToPrometheusPlainAndSummaryMetrics(t *testing.T) {
+	bm := bigquery.NewBenchmark("BenchmarkFoo", 10, true)
+	bm.AddCondition("GOMAXPROCS", "8")
+	bm.Metadata = &bigquery.Metadata{CL: "abc123"}
+	bm.AddMetric("ns/op", "ns/op", 100)
+	bm.AddMetricSamples("latency", "ms", &bigquery.MetricSamples{
+		Count:  3,
+		Mean:   50,
+		Stddev: 5,
+		P50:    49,
+		P90:    55,
+		P99:    59,
+	})
+
+	var buf bytes.Buffer
+	if err := ToPrometheus([]*bigquery.Benchmark{bm}, &buf); err != nil {
+		t.Fatalf("ToPrometheus returned error: %v", err)
+	}
+
+	const labels = `GOMAXPROCS="8",cl="abc123",name="BenchmarkFoo"`
+	want := "" +
+		"# HELP ns_op ns/op, in ns/op.\n" +
+		"# TYPE ns_op gauge\n" +
+		"ns_op{" + labels + "} 100\n" +
+		"# HELP latency latency, in ms.\n" +
+		"# TYPE latency summary\n" +
+		"latency{" + labels + `,quantile="0.5"} 49` + "\n" +
+		"latency{" + labels + `,quantile="0.9"} 55` + "\n" +
+		"latency{" + labels + `,quantile="0.99"} 59` + "\n" +
+		"latency_sum{" + labels + "} 150\n" +
+		"latency_count{" + labels + "} 3\n" +
+		"# HELP latency_stddev standard deviation of latency, in ms.\n" +
+		"# TYPE latency_stddev gauge\n" +
+		"latency_stddev{" + labels + "} 5\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("ToPrometheus output mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPromLabelsSorted(t *testing.T) {
+	bm := bigquery.NewBenchmark("BenchmarkFoo", 10, true)
+	bm.AddCondition("GOMAXPROCS", "8")
+	bm.AddCondition("workload", "web")
+	bm.Metadata = &bigquery.Metadata{CL: "abc123", Config: map[string]string{"go": "go1.17"}}
+
+	want := `GOMAXPROCS="8",cl="abc123",go="go1.17",name="BenchmarkFoo",workload="web"`
+	if got := promLabels(bm); got != want {
+		t.Errorf("promLabels() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quantile implements a streaming, approximate quantile estimator
+// based on the Cormode-Korn-Muthukrishnan biased quantiles algorithm, so a
+// long-running benchmark decoder can summarize repeated samples of a
+// metric (e.g. p50/p90/p99) without buffering every raw sample.
+//
+// A Stream maintains a sorted list of tuples (v, g, delta), where g is the
+// number of observations since the previous stored tuple and delta bounds
+// the rank error for v. Inserting a value finds its position, assigns
+// delta = floor(2*eps*rank) for interior points (0 at the extremes), and
+// periodically compresses adjacent tuples whose combined rank error still
+// fits within the eps bound. Querying a quantile walks the list until the
+// cumulative g exceeds the target rank (within the tuple's delta).
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// sample is one stored tuple in a Stream's summary.
+type sample struct {
+	v     float64
+	g     int
+	delta int
+}
+
+// Stream is a single streaming quantile sketch, accurate to within eps of
+// the true rank for any quantile queried with Query.
+type Stream struct {
+	eps     float64
+	samples []sample
+	n       int
+}
+
+// New returns a Stream that estimates quantiles to within eps of the true
+// rank, e.g. eps=0.01 bounds every query to within 1% of the sample count.
+func New(eps float64) *Stream {
+	return &Stream{eps: eps}
+}
+
+// Insert adds v to the sketch.
+func (s *Stream) Insert(v float64) {
+	i := sort.Search(len(s.samples), func(i int) bool { return s.samples[i].v >= v })
+
+	rank := 0
+	for j := 0; j < i; j++ {
+		rank += s.samples[j].g
+	}
+	delta := 0
+	if i != 0 && i != len(s.samples) {
+		delta = int(math.Floor(2 * s.eps * float64(rank)))
+	}
+
+	s.samples = append(s.samples, sample{})
+	copy(s.samples[i+1:], s.samples[i:])
+	s.samples[i] = sample{v: v, g: 1, delta: delta}
+	s.n++
+
+	if band := int(1 / (2 * s.eps)); band > 0 && s.n%band == 0 {
+		s.compress()
+	}
+}
+
+// compress merges adjacent tuples that can be combined without violating
+// the eps rank-error bound.
+func (s *Stream) compress() {
+	if len(s.samples) < 3 {
+		return
+	}
+	threshold := func(rank int) int { return int(math.Floor(2 * s.eps * float64(rank))) }
+
+	out := s.samples[:1]
+	rank := s.samples[0].g
+	for i := 1; i < len(s.samples)-1; i++ {
+		cur, next := s.samples[i], s.samples[i+1]
+		if cur.g+next.g+next.delta <= threshold(rank+cur.g) {
+			s.samples[i+1].g += cur.g
+			continue
+		}
+		out = append(out, cur)
+		rank += cur.g
+	}
+	s.samples = append(out, s.samples[len(s.samples)-1])
+}
+
+// Query returns the approximate value at quantile phi, 0 <= phi <= 1.
+func (s *Stream) Query(phi float64) float64 {
+	if len(s.samples) == 0 {
+		return 0
+	}
+	target := phi * float64(s.n)
+	rank := 0.0
+	for _, smp := range s.samples {
+		rank += float64(smp.g)
+		if rank > target+float64(smp.delta)/2 {
+			return smp.v
+		}
+	}
+	return s.samples[len(s.samples)-1].v
+}
+
+// Count returns the number of values inserted into s.
+func (s *Stream) Count() int { return s.n }
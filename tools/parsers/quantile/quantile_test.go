@@ -0,0 +1,108 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestQuantileOrderedInserts(t *testing.T) {
+	s := New(0.01)
+	const n = 1000
+	for i := 0; i < n; i++ {
+		s.Insert(float64(i))
+	}
+	if got := s.Count(); got != n {
+		t.Fatalf("Count() = %d, want %d", got, n)
+	}
+	for _, tc := range []struct {
+		phi  float64
+		want float64
+	}{
+		{0.5, 500},
+		{0.9, 900},
+		{0.99, 990},
+	} {
+		if got := s.Query(tc.phi); math.Abs(got-tc.want) > 0.02*n {
+			t.Errorf("Query(%v) = %v, want within 2%% of %v", tc.phi, got, tc.want)
+		}
+	}
+}
+
+func TestQuantileShuffledInserts(t *testing.T) {
+	s := New(0.01)
+	const n = 2000
+	values := rand.New(rand.NewSource(1)).Perm(n)
+	for _, v := range values {
+		s.Insert(float64(v))
+	}
+	if got := s.Count(); got != n {
+		t.Fatalf("Count() = %d, want %d", got, n)
+	}
+	// The sketch is only approximate, but the median of a uniform
+	// permutation of [0, n) should land close to n/2.
+	if got, want := s.Query(0.5), float64(n)/2; math.Abs(got-want) > 0.05*float64(n) {
+		t.Errorf("Query(0.5) = %v, want within 5%% of %v", got, want)
+	}
+}
+
+func TestQuantileEmpty(t *testing.T) {
+	s := New(0.01)
+	if got := s.Query(0.5); got != 0 {
+		t.Errorf("Query(0.5) on empty Stream = %v, want 0", got)
+	}
+	if got := s.Count(); got != 0 {
+		t.Errorf("Count() on empty Stream = %v, want 0", got)
+	}
+}
+
+func TestQuantileSingleValue(t *testing.T) {
+	s := New(0.01)
+	s.Insert(42)
+	if got := s.Query(0.5); got != 42 {
+		t.Errorf("Query(0.5) = %v, want 42", got)
+	}
+}
+
+// TestQuantileCompressMonotonic exercises compress() across many inserts
+// (forcing several compaction passes) and checks the invariant compress
+// relies on: samples stay sorted by value and every value inserted is
+// still representable by some quantile query, i.e. compression never
+// drops the running count.
+func TestQuantileCompressMonotonic(t *testing.T) {
+	s := New(0.1) // Small band so compress() runs frequently.
+	const n = 5000
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < n; i++ {
+		s.Insert(r.Float64() * 1000)
+	}
+	if got := s.Count(); got != n {
+		t.Fatalf("Count() = %d, want %d", got, n)
+	}
+	prev := math.Inf(-1)
+	total := 0
+	for _, smp := range s.samples {
+		if smp.v < prev {
+			t.Fatalf("samples out of order: %v before %v", prev, smp.v)
+		}
+		prev = smp.v
+		total += smp.g
+	}
+	if total != n {
+		t.Errorf("sum of g across samples = %d, want %d", total, n)
+	}
+}